@@ -0,0 +1,150 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// readyPollInterval is how often WaitStarted re-checks a HealthChecker
+// while a dependent is blocked waiting for it to come up.
+const readyPollInterval = 10 * time.Millisecond
+
+// serviceName returns the identity used for dependency resolution: the
+// value from Named if the service implements it, otherwise a synthetic
+// name scoped to its position in the group. Only named services can
+// usefully be depended on, since the synthetic name isn't known outside
+// the group.
+func serviceName(index int, svc Service) string {
+	if n, ok := svc.(Named); ok {
+		return n.Name()
+	}
+	return fmt.Sprintf("#%d", index)
+}
+
+func dependsOn(svc Service) []string {
+	if d, ok := svc.(DependencyAware); ok {
+		return d.DependsOn()
+	}
+	return nil
+}
+
+// Levels groups n items, indexed 0..n-1, into dependency-ordered start
+// levels with Kahn's algorithm: every item in a level is independent of
+// the others in the same level and can start concurrently, and an item
+// only joins a level once everything named by deps(i) already sits in an
+// earlier one. name(i) identifies item i for dependency resolution and
+// cycle diagnostics; a dependency naming something outside [0,n) is
+// ignored. A cycle aborts with a diagnostic listing the items that could
+// never be scheduled.
+//
+// Levels is shared by every package in this tree that orders service/server
+// startup by declared dependencies (core/service, the demo Manager, the
+// module-root ServiceGroup), so a fix to the algorithm or its diagnostics
+// only has to land once.
+func Levels(n int, name func(i int) string, deps func(i int) []string) ([][]int, error) {
+	names := make([]string, n)
+	byName := make(map[string]int, n)
+	for i := 0; i < n; i++ {
+		names[i] = name(i)
+		byName[names[i]] = i
+	}
+
+	indegree := make([]int, n)
+	children := make(map[int][]int, n)
+	for i := 0; i < n; i++ {
+		for _, dep := range deps(i) {
+			j, ok := byName[dep]
+			if !ok {
+				continue // depends on something outside this group, ignore.
+			}
+			indegree[i]++
+			children[j] = append(children[j], i)
+		}
+	}
+
+	var levels [][]int
+	visited := make([]bool, n)
+	for remaining := n; remaining > 0; {
+		var level []int
+		for i := 0; i < n; i++ {
+			if !visited[i] && indegree[i] == 0 {
+				level = append(level, i)
+			}
+		}
+		if len(level) == 0 {
+			return nil, fmt.Errorf("service: dependency cycle detected, unresolved services: %v",
+				pendingNames(names, visited))
+		}
+
+		for _, i := range level {
+			visited[i] = true
+			remaining--
+			for _, c := range children[i] {
+				indegree[c]--
+			}
+		}
+		levels = append(levels, level)
+	}
+
+	return levels, nil
+}
+
+func pendingNames(names []string, visited []bool) []string {
+	var out []string
+	for i, n := range names {
+		if !visited[i] {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// buildLevels groups services into start levels, resolving Levels' indices
+// back to the concrete Service at each index. Used by doStop, which only
+// needs ordering, not the per-service done-channel synchronization doStart
+// uses to gate dependents.
+func buildLevels(services []Service) ([][]Service, error) {
+	names := make([]string, len(services))
+	depNames := make([][]string, len(services))
+	for i, svc := range services {
+		names[i] = serviceName(i, svc)
+		depNames[i] = dependsOn(svc)
+	}
+
+	idxLevels, err := Levels(len(services),
+		func(i int) string { return names[i] },
+		func(i int) []string { return depNames[i] })
+	if err != nil {
+		return nil, err
+	}
+
+	levels := make([][]Service, len(idxLevels))
+	for i, idx := range idxLevels {
+		level := make([]Service, len(idx))
+		for j, k := range idx {
+			level[j] = services[k]
+		}
+		levels[i] = level
+	}
+
+	return levels, nil
+}
+
+// WaitStarted blocks until done is closed, signalling that svc's Start has
+// returned, and then -- if svc implements HealthChecker -- polls Ready
+// until it reports healthy. A svc that doesn't implement HealthChecker is
+// considered ready as soon as done closes, since that's the only signal
+// available for it; without waiting on done first, such a svc would never
+// be synchronized on at all.
+func WaitStarted(done <-chan struct{}, svc interface{}) {
+	<-done
+
+	hc, ok := svc.(HealthChecker)
+	if !ok {
+		return
+	}
+
+	for !hc.Ready() {
+		time.Sleep(readyPollInterval)
+	}
+}
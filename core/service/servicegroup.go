@@ -25,9 +25,33 @@ type (
 		Stopper
 	}
 
+	// Named is implemented by services that want to take part in
+	// dependency-graph ordered startup. Services that don't implement it
+	// still start, just without dependency guarantees.
+	Named interface {
+		Name() string
+	}
+
+	// DependencyAware is implemented by services that must wait for other
+	// named services to start (and become healthy, see HealthChecker)
+	// before starting themselves.
+	DependencyAware interface {
+		DependsOn() []string
+	}
+
+	// HealthChecker is implemented by services whose Start returning
+	// doesn't yet mean the service is usable. Dependents block until
+	// Ready returns true before they start.
+	HealthChecker interface {
+		Ready() bool
+	}
+
 	// A ServiceGroup is a group of services.
-	// Attention: the starting order of the added services is not guaranteed.
-	ServiceGroup struct {   //服务组：无法保证服务启动顺序
+	// Services are started in dependency order: independent services start
+	// concurrently, and a service implementing DependencyAware blocks until
+	// the services named by DependsOn have started and become Ready. They
+	// are stopped in the reverse of that order.
+	ServiceGroup struct {   //服务组：按依赖关系分层启停
 		services []Service
 		stopOnce func()
 	}
@@ -59,26 +83,74 @@ func (sg *ServiceGroup) Start() {
 }
 
 // Stop stops the ServiceGroup.
-func (sg *ServiceGroup) Stop() {NewCache
+func (sg *ServiceGroup) Stop() {
 	sg.stopOnce()
 }
 
 func (sg *ServiceGroup) doStart() {
-	routineGroup := threading.NewRoutineGroup()
+	n := len(sg.services)
+	names := make([]string, n)
+	depNames := make([][]string, n)
+	byName := make(map[string]int, n)
+	for i, svc := range sg.services {
+		names[i] = serviceName(i, svc)
+		depNames[i] = dependsOn(svc)
+		byName[names[i]] = i
+	}
+
+	// Levels is only used here to fail fast on a dependency cycle; a
+	// real Service.Start typically blocks forever (e.g. serving
+	// requests), so nothing here can wait for a level to "finish"
+	// starting before moving on to the next one. Instead every service
+	// starts concurrently in its own goroutine, closing done[i] once its
+	// own Start call returns, and a dependent gates its own Start call
+	// on its dependencies' done (and, if they implement HealthChecker,
+	// their Ready) via WaitStarted -- not on a whole level completing.
+	if _, err := Levels(n, func(i int) string { return names[i] }, func(i int) []string { return depNames[i] }); err != nil {
+		log.Fatal(err)
+	}
+
+	done := make([]chan struct{}, n)
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
 
 	for i := range sg.services {
-		service := sg.services[i]
-		routineGroup.RunSafe(func() {
-			service.Start()
+		i := i
+		svc := sg.services[i]
+		threading.GoSafe(func() {
+			defer close(done[i])
+
+			for _, depName := range depNames[i] {
+				if j, ok := byName[depName]; ok {
+					WaitStarted(done[j], sg.services[j])
+				}
+			}
+			svc.Start()
 		})
 	}
 
-	routineGroup.Wait()
+	// Start is a blocking call: return only once every service's Start has.
+	for _, d := range done {
+		<-d
+	}
 }
 
 func (sg *ServiceGroup) doStop() {
-	for _, service := range sg.services {
-		service.Stop()
+	levels, err := buildLevels(sg.services)
+	if err != nil {
+		// can't resolve a dependency order, fall back to insertion order,
+		// which Add already keeps reversed.
+		for _, service := range sg.services {
+			service.Stop()
+		}
+		return
+	}
+
+	for i := len(levels) - 1; i >= 0; i-- {
+		for _, service := range levels[i] {
+			service.Stop()
+		}
 	}
 }
 
@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/hash"
+	"github.com/zeromicro/go-zero/core/syncx"
+)
+
+// defaultRehomeExpire is the expire used when lazily re-homing a
+// cross-node hit onto its primary node.
+const defaultRehomeExpire = time.Hour
+
+// aggregatorCache shards by key exactly like cacheCluster, but GetCtx
+// falls back to searching every other node before giving up, which
+// papers over a ring that's mid-rebalance and hasn't finished moving a
+// key to its new primary node yet. Every method other than Get and GetCtx
+// is inherited unchanged from cacheCluster.
+type aggregatorCache struct {
+	cacheCluster
+	nodes         []Cache
+	stat          *Stat
+	rehomeExpire  time.Duration
+	crossNodeHits int64
+}
+
+// AggregatorCache is the Cache returned by NewAggregator, with CrossNodeHits
+// exported so operators can watch for ring drift during a rebalance.
+type AggregatorCache interface {
+	Cache
+	CrossNodeHits() int64
+}
+
+// NewAggregator returns a Cache that shards by key like New, but on a
+// miss from the key's primary node also searches every other node before
+// giving up, and lazily re-homes a cross-node hit onto the primary node
+// with SetWithExpireCtx. Useful during rebalancing/resharding of the ring,
+// where keys may temporarily live on a node other than the one the
+// dispatcher now picks for them.
+func NewAggregator(c ClusterConf, barrier syncx.SingleFlight, st *Stat, errNotFound error,
+	opts ...Option) AggregatorCache {
+	if len(c) == 0 || TotalWeights(c) <= 0 {
+		log.Fatal("no cache nodes")
+	}
+
+	dispatcher := hash.NewConsistentHash()
+	nodes := make([]Cache, 0, len(c))
+	for _, node := range c {
+		cn := NewNode(node.NewRedis(), barrier, st, errNotFound, opts...)
+		dispatcher.AddWithWeight(cn, node.Weight)
+		nodes = append(nodes, cn)
+	}
+
+	return &aggregatorCache{
+		cacheCluster: cacheCluster{
+			dispatcher:  dispatcher,
+			errNotFound: errNotFound,
+		},
+		nodes:        nodes,
+		stat:         st,
+		rehomeExpire: defaultRehomeExpire,
+	}
+}
+
+// Get gets the cache with key and fills into v. Defined explicitly
+// instead of relying on the one promoted from the embedded cacheCluster:
+// that one calls cc.GetCtx on the embedded value directly, which Go
+// resolves statically to cacheCluster.GetCtx and never reaches the
+// cross-node fallback below.
+func (ac *aggregatorCache) Get(key string, val interface{}) error {
+	return ac.GetCtx(context.Background(), key, val)
+}
+
+// GetCtx gets the cache with key and fills into v. If the primary node
+// the dispatcher picked for key doesn't have it, every other node is
+// searched in parallel (bounded by the node count, and cancelled as soon
+// as ctx is done or a hit comes back); the first hit found this way is
+// recorded as a cross-node hit and lazily re-homed onto the primary node.
+func (ac *aggregatorCache) GetCtx(ctx context.Context, key string, val interface{}) error {
+	primary, ok := ac.dispatcher.Get(key)
+	if !ok {
+		return ac.errNotFound
+	}
+
+	primaryCache := primary.(Cache)
+	err := primaryCache.GetCtx(ctx, key, val)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, ac.errNotFound) {
+		return err
+	}
+
+	found, err := ac.searchOtherNodes(ctx, primaryCache, key, val)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ac.errNotFound
+	}
+
+	return nil
+}
+
+// searchOtherNodes fans GetCtx out to every node but primary, returning
+// as soon as one of them has key, or ctx is done, or all of them miss.
+func (ac *aggregatorCache) searchOtherNodes(ctx context.Context, primary Cache, key string, val interface{}) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		val interface{}
+		err error
+	}
+
+	results := make(chan result, len(ac.nodes))
+	pending := 0
+	for _, node := range ac.nodes {
+		if node == primary {
+			continue
+		}
+
+		pending++
+		go func(node Cache) {
+			v := reflect.New(reflect.TypeOf(val).Elem()).Interface()
+			err := node.GetCtx(ctx, key, v)
+			results <- result{val: v, err: err}
+		}(node)
+	}
+
+	var firstErr error
+	for i := 0; i < pending; i++ {
+		select {
+		case r := <-results:
+			if r.err == nil {
+				if err := copyVal(val, r.val); err != nil {
+					return false, err
+				}
+
+				atomic.AddInt64(&ac.crossNodeHits, 1)
+				ac.stat.IncrementHit()
+				ac.rehome(primary, key, r.val)
+				return true, nil
+			}
+			if firstErr == nil && !errors.Is(r.err, ac.errNotFound) {
+				firstErr = r.err
+			}
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+
+	return false, firstErr
+}
+
+// rehome writes val onto primary in the background so GetCtx doesn't pay
+// for it; a failure here just means the next lookup searches again.
+func (ac *aggregatorCache) rehome(primary Cache, key string, val interface{}) {
+	go func() {
+		if err := primary.SetWithExpireCtx(context.Background(), key, val, ac.rehomeExpire); err != nil {
+			log.Printf("cache: aggregator rehome of %q failed: %v", key, err)
+		}
+	}()
+}
+
+// CrossNodeHits returns how many GetCtx calls were served by the fallback
+// search across non-primary nodes instead of the primary one the
+// dispatcher picked -- a signal operators can watch for ring drift during
+// a rebalance, the same way Stat already surfaces hit/miss counts.
+func (ac *aggregatorCache) CrossNodeHits() int64 {
+	return atomic.LoadInt64(&ac.crossNodeHits)
+}
+
+var _ AggregatorCache = (*aggregatorCache)(nil)
@@ -0,0 +1,261 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"reflect"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/jsonx"
+	"github.com/zeromicro/go-zero/core/syncx"
+)
+
+const (
+	// defaultL1Expire is how long a back-filled L1 entry lives before the
+	// jitter in jitterExpire is applied.
+	defaultL1Expire = 30 * time.Second
+	// l1JitterRatio shrinks the L1 TTL by up to this fraction so that
+	// back-filled entries across a fleet of processes don't all expire at
+	// the same instant.
+	l1JitterRatio = 0.2
+	// defaultInvalidateChannel is the pub/sub channel DelCtx publishes on
+	// when a PubSubClient is configured via WithInvalidation.
+	defaultInvalidateChannel = "cache:multitier:invalidate"
+)
+
+type (
+	// PubSubClient is the minimal pub/sub surface MultiTier needs to tell
+	// peer processes to evict an L1 entry after a DelCtx; a *redis.Redis
+	// satisfies it via its Publish/Subscribe helpers.
+	PubSubClient interface {
+		Publish(channel, payload string) (int, error)
+		Subscribe(channel string, onMessage func(payload string)) error
+	}
+
+	// MultiTierOption customizes a multiTier built with NewMultiTier.
+	MultiTierOption func(*multiTier)
+
+	// multiTier fronts a remote Cache (typically Redis-backed, e.g. a
+	// cacheCluster) with an in-process local Cache, so hot keys don't
+	// round-trip to Redis on every read.
+	multiTier struct {
+		local   Cache
+		remote  Cache
+		barrier syncx.SingleFlight
+		pub     PubSubClient
+		channel string
+	}
+)
+
+// NewMultiTier returns a Cache that checks local first and falls back to
+// remote on a miss, back-filling local with a shorter, jittered TTL.
+// DelCtx always evicts from both tiers; pass WithInvalidation to also
+// publish the deleted keys on a Redis pub/sub channel so peer processes
+// evict the same keys from their own local tier.
+func NewMultiTier(local, remote Cache, opts ...MultiTierOption) Cache {
+	mt := &multiTier{
+		local:   local,
+		remote:  remote,
+		barrier: syncx.NewSingleFlight(),
+		channel: defaultInvalidateChannel,
+	}
+	for _, opt := range opts {
+		opt(mt)
+	}
+
+	if mt.pub != nil {
+		if err := mt.pub.Subscribe(mt.channel, mt.onInvalidate); err != nil {
+			log.Printf("cache: multitier subscribe to %q failed: %v", mt.channel, err)
+		}
+	}
+
+	return mt
+}
+
+// WithInvalidation makes DelCtx publish the deleted keys on channel via
+// client, and subscribes this multiTier to the same channel so it evicts
+// its own local tier when a peer process deletes a key first.
+func WithInvalidation(client PubSubClient, channel string) MultiTierOption {
+	return func(mt *multiTier) {
+		mt.pub = client
+		if len(channel) > 0 {
+			mt.channel = channel
+		}
+	}
+}
+
+// Del deletes cached values with keys.
+func (mt *multiTier) Del(keys ...string) error {
+	return mt.DelCtx(context.Background(), keys...)
+}
+
+// DelCtx deletes cached values with keys from both tiers, and publishes
+// the deletion so peer processes can evict their own local tier too.
+func (mt *multiTier) DelCtx(ctx context.Context, keys ...string) error {
+	if err := mt.remote.DelCtx(ctx, keys...); err != nil {
+		return err
+	}
+	if err := mt.local.DelCtx(ctx, keys...); err != nil {
+		return err
+	}
+
+	mt.publishInvalidation(keys)
+	return nil
+}
+
+// Get gets the cache with key and fills into v.
+func (mt *multiTier) Get(key string, val interface{}) error {
+	return mt.GetCtx(context.Background(), key, val)
+}
+
+// GetCtx gets the cache with key and fills into v, checking local first
+// and falling back to remote, back-filling local on a remote hit.
+func (mt *multiTier) GetCtx(ctx context.Context, key string, val interface{}) error {
+	if err := mt.local.GetCtx(ctx, key, val); err == nil {
+		return nil
+	}
+
+	if err := mt.remote.GetCtx(ctx, key, val); err != nil {
+		return err
+	}
+
+	mt.backfill(ctx, key, val)
+	return nil
+}
+
+// IsNotFound checks if the given error is the defined errNotFound of
+// either tier.
+func (mt *multiTier) IsNotFound(err error) bool {
+	return mt.remote.IsNotFound(err) || mt.local.IsNotFound(err)
+}
+
+// Set sets the cache with key and v in both tiers.
+func (mt *multiTier) Set(key string, val interface{}) error {
+	return mt.SetCtx(context.Background(), key, val)
+}
+
+// SetCtx sets the cache with key and v in both tiers.
+func (mt *multiTier) SetCtx(ctx context.Context, key string, val interface{}) error {
+	if err := mt.remote.SetCtx(ctx, key, val); err != nil {
+		return err
+	}
+
+	return mt.local.SetCtx(ctx, key, val)
+}
+
+// SetWithExpire sets the cache with key and v in both tiers, using given
+// expire for remote and a shorter, jittered version of it for local.
+func (mt *multiTier) SetWithExpire(key string, val interface{}, expire time.Duration) error {
+	return mt.SetWithExpireCtx(context.Background(), key, val, expire)
+}
+
+// SetWithExpireCtx sets the cache with key and v in both tiers, using
+// given expire for remote and a shorter, jittered version of it for local.
+func (mt *multiTier) SetWithExpireCtx(ctx context.Context, key string, val interface{}, expire time.Duration) error {
+	if err := mt.remote.SetWithExpireCtx(ctx, key, val, expire); err != nil {
+		return err
+	}
+
+	return mt.local.SetWithExpireCtx(ctx, key, val, jitterExpire(expire))
+}
+
+// Take takes the result from cache first, if not found, query from DB and
+// set cache, then return the result.
+func (mt *multiTier) Take(val interface{}, key string, query func(val interface{}) error) error {
+	return mt.TakeCtx(context.Background(), val, key, query)
+}
+
+// TakeCtx takes the result from cache first, if not found, query from DB
+// and set cache, then return the result.
+func (mt *multiTier) TakeCtx(ctx context.Context, val interface{}, key string, query func(val interface{}) error) error {
+	return mt.TakeWithExpireCtx(ctx, val, key, func(val interface{}, expire time.Duration) error {
+		return query(val)
+	})
+}
+
+// TakeWithExpire takes the result from cache first, if not found, query
+// from DB and set cache using given expire, then return the result.
+func (mt *multiTier) TakeWithExpire(val interface{}, key string, query func(val interface{}, expire time.Duration) error) error {
+	return mt.TakeWithExpireCtx(context.Background(), val, key, query)
+}
+
+// TakeWithExpireCtx takes the result from cache first, if not found,
+// query from DB and set cache using given expire, then return the result.
+// Concurrent callers for the same key that all miss both tiers coalesce
+// onto a single remote query via the SingleFlight barrier.
+func (mt *multiTier) TakeWithExpireCtx(ctx context.Context, val interface{}, key string,
+	query func(val interface{}, expire time.Duration) error) error {
+	if err := mt.local.GetCtx(ctx, key, val); err == nil {
+		return nil
+	}
+
+	raw, err := mt.barrier.Do(key, func() (interface{}, error) {
+		v := reflect.New(reflect.TypeOf(val).Elem()).Interface()
+		if err := mt.remote.TakeWithExpireCtx(ctx, v, key, query); err != nil {
+			return nil, err
+		}
+
+		mt.backfill(ctx, key, v)
+		return v, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return copyVal(val, raw)
+}
+
+// backfill writes val into local with a shorter, jittered TTL than
+// defaultL1Expire, logging rather than failing the caller on error, since
+// local is an optimization, not the source of truth.
+func (mt *multiTier) backfill(ctx context.Context, key string, val interface{}) {
+	if err := mt.local.SetWithExpireCtx(ctx, key, val, jitterExpire(defaultL1Expire)); err != nil {
+		log.Printf("cache: multitier backfill of %q failed: %v", key, err)
+	}
+}
+
+func (mt *multiTier) publishInvalidation(keys []string) {
+	if mt.pub == nil {
+		return
+	}
+
+	for _, key := range keys {
+		if _, err := mt.pub.Publish(mt.channel, key); err != nil {
+			log.Printf("cache: multitier publish invalidation of %q failed: %v", key, err)
+		}
+	}
+}
+
+// onInvalidate is called for every message received on the invalidation
+// channel, including the ones this process itself published; evicting a
+// key that's already gone is harmless.
+func (mt *multiTier) onInvalidate(key string) {
+	if err := mt.local.DelCtx(context.Background(), key); err != nil {
+		log.Printf("cache: multitier local evict of %q failed: %v", key, err)
+	}
+}
+
+// jitterExpire shrinks expire by a random amount up to l1JitterRatio, so
+// that back-filled entries don't all expire in lockstep.
+func jitterExpire(expire time.Duration) time.Duration {
+	if expire <= 0 {
+		return defaultL1Expire
+	}
+
+	jitter := time.Duration(rand.Float64() * l1JitterRatio * float64(expire))
+	return expire - jitter
+}
+
+// copyVal copies src into dst by round-tripping through JSON, used to fan
+// a single SingleFlight result out to every waiting caller's own val.
+func copyVal(dst, src interface{}) error {
+	b, err := jsonx.Marshal(src)
+	if err != nil {
+		return err
+	}
+
+	return jsonx.Unmarshal(b, dst)
+}
+
+var _ Cache = (*multiTier)(nil)
@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// KeyBuilder builds the cache keys a Repository[T] reads and writes.
+	// ID builds the key for a single row; List builds the key *prefix*
+	// for list queries, Repository appends a hash of the query
+	// parameters so different where/cols/order/page/rows combinations
+	// don't collide.
+	KeyBuilder struct {
+		ID   func(id interface{}) string
+		List func() string
+	}
+
+	// Loader is the backing store a Repository[T] reads from on a cache
+	// miss; any data-access layer (sqlx, an ORM, ...) can implement it.
+	Loader[T any] interface {
+		FindOne(ctx context.Context, id interface{}) (T, error)
+		FindMany(ctx context.Context, wheres map[string]interface{}, cols []string, order string, page, rows int) ([]T, error)
+	}
+
+	// Repository is a generic, cached read path over a Loader[T],
+	// analogous to a BaseService: GetByID caches the single row under
+	// KeyBuilder.ID, List caches each distinct query's result set under
+	// KeyBuilder.List plus a hash of its parameters, and Invalidate
+	// evicts both on writes.
+	Repository[T any] struct {
+		cache  Typed[T]
+		loader Loader[T]
+		keys   KeyBuilder
+		expire time.Duration
+
+		mu       sync.Mutex
+		listKeys map[string]time.Time // key -> when it was cached, for pruning
+	}
+)
+
+// NewRepository returns a Repository[T] backed by loader, caching through
+// c and expiring list entries after expire.
+func NewRepository[T any](c Cache, loader Loader[T], keys KeyBuilder, expire time.Duration) *Repository[T] {
+	return &Repository[T]{
+		cache:    NewTyped[T](c),
+		loader:   loader,
+		keys:     keys,
+		expire:   expire,
+		listKeys: make(map[string]time.Time),
+	}
+}
+
+// GetByID returns the row for id, taking it from cache first and falling
+// back to loader.FindOne on a miss.
+func (r *Repository[T]) GetByID(ctx context.Context, id interface{}) (T, error) {
+	return r.cache.Take(ctx, r.keys.ID(id), func() (T, error) {
+		return r.loader.FindOne(ctx, id)
+	})
+}
+
+// List returns the rows matching wheres, taking them from cache first and
+// falling back to loader.FindMany on a miss. The result is cached under a
+// key derived from every parameter, so two different queries never share
+// a cache entry. Routed through the embedded Cache's take path
+// (TakeWithExpireCtx here, TakeCtx via Typed.Take for GetByID), so
+// concurrent callers for an uncached query coalesce onto a single FindMany
+// call instead of all hitting the loader at once. A freshly loaded result
+// is then stored again under r.expire.
+func (r *Repository[T]) List(ctx context.Context, wheres map[string]interface{}, cols []string, order string, page, rows int) ([]T, error) {
+	key := r.listKey(wheres, cols, order, page, rows)
+	r.trackListKey(key)
+
+	var list []T
+	fetched := false
+	err := r.cache.TakeWithExpireCtx(ctx, &list, key, func(val interface{}, expire time.Duration) error {
+		loaded, err := r.loader.FindMany(ctx, wheres, cols, order, page, rows)
+		if err != nil {
+			return err
+		}
+
+		*(val.(*[]T)) = loaded
+		fetched = true
+		return nil
+	})
+	if err == nil && fetched {
+		// the take path stored it under the Cache's own expiry.
+		err = r.cache.SetWithExpireCtx(ctx, key, list, r.expire)
+	}
+
+	return list, err
+}
+
+// Invalidate evicts the cached row for id plus every list result this
+// Repository has cached, since a write may change which rows any of them
+// match.
+func (r *Repository[T]) Invalidate(ctx context.Context, id interface{}) error {
+	keys := []string{r.keys.ID(id)}
+
+	r.mu.Lock()
+	for key := range r.listKeys {
+		keys = append(keys, key)
+	}
+	r.listKeys = make(map[string]time.Time)
+	r.mu.Unlock()
+
+	return r.cache.DelCtx(ctx, keys...)
+}
+
+// trackListKey records key as cached and opportunistically prunes entries
+// older than r.expire, since the underlying Redis entry has expired by
+// then regardless: without this, listKeys grows forever for any caller
+// that Lists with varying filters/paging and never calls Invalidate.
+func (r *Repository[T]) trackListKey(key string) {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.listKeys[key] = now
+	for k, at := range r.listKeys {
+		if now.Sub(at) > r.expire {
+			delete(r.listKeys, k)
+		}
+	}
+}
+
+// listKey derives a cache key for a List call from every parameter that
+// affects its result, so distinct queries land on distinct keys.
+func (r *Repository[T]) listKey(wheres map[string]interface{}, cols []string, order string, page, rows int) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s|%v|%s|%d|%d", sortedWheres(wheres), cols, order, page, rows)
+	return fmt.Sprintf("%s%x", r.keys.List(), h.Sum64())
+}
+
+// sortedWheres renders wheres as a deterministic string, since Go's map
+// iteration order is randomized and would otherwise hash the same query
+// to a different key on every call.
+func sortedWheres(wheres map[string]interface{}) string {
+	names := make([]string, 0, len(wheres))
+	for k := range wheres {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	out := make([]string, 0, len(names))
+	for _, k := range names {
+		out = append(out, fmt.Sprintf("%s=%v", k, wheres[k]))
+	}
+	return fmt.Sprint(out)
+}
@@ -0,0 +1,314 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/syncx"
+)
+
+type (
+	// Node is one resolved level of a Tree walk: Key is the cache key
+	// Name produced for that level, Value is whatever Init resolved for
+	// it. Children key off Value via the parent *Node passed to their
+	// own Name/Init, so e.g. a region level can read the tenant id off
+	// the parent Node its Init was given.
+	Node struct {
+		Key   string
+		Value interface{}
+	}
+
+	// Level is one layer of a Tree, built for a single path segment.
+	// Name derives this level's cache key from the parent Node (nil for
+	// the root level); Init resolves the value for that key on a cache
+	// miss, typically by querying a DB or downstream service using
+	// whatever the parent Node carries. Decode rebuilds the same concrete
+	// type Init produced from the raw bytes a prior Init's Value was
+	// marshaled to, so a level reading parent.Value off a cache hit sees
+	// the same shape it would have off a fresh Init instead of a generic
+	// map[string]interface{}.
+	Level interface {
+		Name(ctx context.Context, parent *Node) (string, error)
+		Init(ctx context.Context, parent *Node) (*Node, error)
+		Decode(raw []byte) (interface{}, error)
+	}
+
+	// LevelFactory builds the Level for one path segment; Tree.Get calls
+	// it once per call, positionally matching path[i] against levels[i].
+	LevelFactory func(segment string) Level
+
+	// TreeOption customizes a Tree built with NewTree.
+	TreeOption func(*Tree)
+
+	// cachedNode is what's actually stored in leaf: Value is kept as raw
+	// JSON rather than decoded generically, so resolve can hand it to the
+	// owning Level's Decode to get back the original concrete type.
+	cachedNode struct {
+		Key   string
+		Value json.RawMessage
+	}
+
+	// Tree composes LevelFactories into a single cache keyed by a path of
+	// segments, caching (and single-flighting) each intermediate result
+	// under its own TTL instead of only the final leaf value. leaf is the
+	// only thing that actually talks to Redis; pass it a Cache built by
+	// New with a multi-node ClusterConf to keep sharding leaves across
+	// nodes through the existing hash.ConsistentHash dispatcher. This
+	// lets a multi-step lookup like tenant -> region -> service ->
+	// instance collapse into one composed cache instead of N flat
+	// Cache.GetCtx calls.
+	Tree struct {
+		levels  []LevelFactory
+		leaf    Cache
+		ttl     time.Duration
+		barrier syncx.SingleFlight
+		sep     string
+		pub     PubSubClient
+		channel string
+
+		mu    sync.Mutex
+		known map[string]time.Time // key -> when it was resolved, for Invalidate
+	}
+)
+
+// defaultTreeInvalidateChannel is the pub/sub channel Invalidate publishes
+// on when a PubSubClient is configured via WithTreeInvalidation.
+const defaultTreeInvalidateChannel = "cache:tree:invalidate"
+
+// NewTree returns a Tree with one Level per entry in levels, caching every
+// intermediate result in leaf for ttl. Pass WithTreeInvalidation to also
+// prune keys a peer instance resolved when Invalidate is called anywhere
+// in the fleet, not just the ones this Tree has itself resolved.
+func NewTree(leaf Cache, ttl time.Duration, levels []LevelFactory, opts ...TreeOption) *Tree {
+	t := &Tree{
+		levels:  levels,
+		leaf:    leaf,
+		ttl:     ttl,
+		barrier: syncx.NewSingleFlight(),
+		sep:     "/",
+		channel: defaultTreeInvalidateChannel,
+		known:   make(map[string]time.Time),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if t.pub != nil {
+		if err := t.pub.Subscribe(t.channel, t.onInvalidate); err != nil {
+			log.Printf("cache: tree subscribe to %q failed: %v", t.channel, err)
+		}
+	}
+
+	return t
+}
+
+// WithTreeInvalidation makes Invalidate publish the prefix it prunes on
+// channel via client, and subscribes this Tree to the same channel so it
+// prunes keys resolved by a peer instance too: each subscriber applies the
+// prefix to its own known map, so a key only one process ever resolved
+// still gets evicted once that process receives the broadcast, instead of
+// Invalidate only ever seeing the calling process's own bookkeeping.
+func WithTreeInvalidation(client PubSubClient, channel string) TreeOption {
+	return func(t *Tree) {
+		t.pub = client
+		if len(channel) > 0 {
+			t.channel = channel
+		}
+	}
+}
+
+// Get walks the tree top-down across path, one segment per registered
+// level, and returns the resolved leaf Node. Each level is cached (and
+// single-flighted) independently, so a miss partway down only re-resolves
+// the levels below the miss.
+func (t *Tree) Get(ctx context.Context, path ...string) (*Node, error) {
+	if len(path) != len(t.levels) {
+		return nil, fmt.Errorf("cache: tree expects %d path segments, got %d", len(t.levels), len(path))
+	}
+
+	node, _, err := t.walk(ctx, path)
+	return node, err
+}
+
+// walk resolves every level named by path in order, returning the final
+// Node plus the cache key segments used to reach it.
+func (t *Tree) walk(ctx context.Context, path []string) (*Node, []string, error) {
+	var parent *Node
+	var segments []string
+	for i, seg := range path {
+		level := t.levels[i](seg)
+
+		name, err := level.Name(ctx, parent)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		segments = append(segments, name)
+		node, err := t.resolve(ctx, strings.Join(segments, t.sep), parent, level)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		parent = node
+	}
+
+	return parent, segments, nil
+}
+
+// resolve returns the cached Node for key, decoding a cache hit's raw
+// Value through level.Decode so it comes back as the same concrete type
+// level.Init produced, or calls level.Init on a miss, coalescing
+// concurrent misses for the same key behind barrier.
+func (t *Tree) resolve(ctx context.Context, key string, parent *Node, level Level) (*Node, error) {
+	var cached cachedNode
+	if err := t.leaf.GetCtx(ctx, key, &cached); err == nil {
+		value, err := level.Decode(cached.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Node{Key: key, Value: value}, nil
+	}
+
+	raw, err := t.barrier.Do(key, func() (interface{}, error) {
+		resolved, err := level.Init(ctx, parent)
+		if err != nil {
+			return nil, err
+		}
+		resolved.Key = key
+
+		valueBytes, err := json.Marshal(resolved.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := cachedNode{Key: key, Value: valueBytes}
+		if err := t.leaf.SetWithExpireCtx(ctx, key, entry, t.ttl); err != nil {
+			log.Printf("cache: tree cache write for %q failed: %v", key, err)
+		}
+		t.trackKey(key)
+
+		return resolved, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return raw.(*Node), nil
+}
+
+// Invalidate prunes the subtree rooted at path: the node at path itself
+// plus every descendant Get has ever cached under it. path may be shorter
+// than the full level count to prune an entire branch at once; an empty
+// path prunes the whole tree. The prefix to prune is derived from
+// Level.Name alone, never Init or a cache read, since the most common
+// reason to invalidate is that the underlying resource is already gone --
+// exactly when Init would be expected to fail.
+func (t *Tree) Invalidate(ctx context.Context, path ...string) error {
+	if len(path) > len(t.levels) {
+		return fmt.Errorf("cache: tree expects at most %d path segments, got %d", len(t.levels), len(path))
+	}
+
+	var prefix string
+	if len(path) > 0 {
+		segments, err := t.nameSegments(ctx, path)
+		if err != nil {
+			return err
+		}
+		prefix = strings.Join(segments, t.sep)
+	}
+
+	if err := t.pruneKnown(ctx, prefix); err != nil {
+		return err
+	}
+
+	t.publishInvalidation(prefix)
+	return nil
+}
+
+// pruneKnown deletes every key in t.known matching prefix from both known
+// and leaf. Called directly by Invalidate for the keys this instance
+// itself resolved, and again by onInvalidate when a peer instance
+// broadcasts the same prefix, so that instance's own known keys -- ones
+// Invalidate's caller could never see -- get evicted too.
+func (t *Tree) pruneKnown(ctx context.Context, prefix string) error {
+	t.mu.Lock()
+	var keys []string
+	for key := range t.known {
+		if prefix == "" || key == prefix || strings.HasPrefix(key, prefix+t.sep) {
+			keys = append(keys, key)
+			delete(t.known, key)
+		}
+	}
+	t.mu.Unlock()
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return t.leaf.DelCtx(ctx, keys...)
+}
+
+func (t *Tree) publishInvalidation(prefix string) {
+	if t.pub == nil {
+		return
+	}
+
+	if _, err := t.pub.Publish(t.channel, prefix); err != nil {
+		log.Printf("cache: tree publish invalidation of %q failed: %v", prefix, err)
+	}
+}
+
+// onInvalidate is called for every message received on the invalidation
+// channel, including the one this process itself just published via
+// Invalidate; pruning a prefix that's already gone from known is harmless.
+func (t *Tree) onInvalidate(prefix string) {
+	if err := t.pruneKnown(context.Background(), prefix); err != nil {
+		log.Printf("cache: tree invalidate broadcast for %q failed: %v", prefix, err)
+	}
+}
+
+// nameSegments derives the cache-key segment for each entry in path using
+// only Level.Name, without ever calling Init or reading the cache. The
+// parent Node threaded between levels carries just the Key built so far
+// (no Value), since computing a real Value would require Init.
+func (t *Tree) nameSegments(ctx context.Context, path []string) ([]string, error) {
+	var parent *Node
+	var segments []string
+	for i, seg := range path {
+		level := t.levels[i](seg)
+
+		name, err := level.Name(ctx, parent)
+		if err != nil {
+			return nil, err
+		}
+
+		segments = append(segments, name)
+		parent = &Node{Key: strings.Join(segments, t.sep)}
+	}
+
+	return segments, nil
+}
+
+// trackKey records key as resolved and opportunistically prunes entries
+// older than t.ttl, since the underlying Redis entry has expired by then
+// regardless: without this, known grows forever for any path with
+// variable segments that's never explicitly Invalidated.
+func (t *Tree) trackKey(key string) {
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.known[key] = now
+	for k, at := range t.known {
+		if now.Sub(at) > t.ttl {
+			delete(t.known, k)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Typed wraps a Cache with compile-time type safety: callers pass and
+// receive a T directly instead of an interface{} destination, with no
+// reflection at the call site. The untyped, interface{}-based methods on
+// Cache stay available through the embedded field for existing callers.
+type Typed[T any] struct {
+	Cache
+}
+
+// NewTyped wraps c as a Typed[T].
+func NewTyped[T any](c Cache) Typed[T] {
+	return Typed[T]{Cache: c}
+}
+
+// Get gets the cache with key and returns it as a T.
+func (t Typed[T]) Get(ctx context.Context, key string) (T, error) {
+	var val T
+	err := t.GetCtx(ctx, key, &val)
+	return val, err
+}
+
+// Set sets the cache with key and val.
+func (t Typed[T]) Set(ctx context.Context, key string, val T) error {
+	return t.SetCtx(ctx, key, val)
+}
+
+// SetWithExpire sets the cache with key and val, using given expire.
+func (t Typed[T]) SetWithExpire(ctx context.Context, key string, val T, expire time.Duration) error {
+	return t.SetWithExpireCtx(ctx, key, val, expire)
+}
+
+// Take takes the result from cache first, if not found, calls query and
+// sets the cache with its result, then returns it.
+func (t Typed[T]) Take(ctx context.Context, key string, query func() (T, error)) (T, error) {
+	var val T
+	err := t.TakeCtx(ctx, &val, key, func(v interface{}) error {
+		loaded, err := query()
+		if err != nil {
+			return err
+		}
+
+		*(v.(*T)) = loaded
+		return nil
+	})
+
+	return val, err
+}
+
+// TakeWithExpire takes the result from cache first, if not found, calls
+// query -- handed the expire the underlying Cache will store the result
+// under -- and sets the cache with its result, then returns it. Concurrent
+// callers for the same key that all miss coalesce onto a single query call
+// via the underlying Cache's SingleFlight barrier.
+func (t Typed[T]) TakeWithExpire(ctx context.Context, key string, query func(expire time.Duration) (T, error)) (T, error) {
+	var val T
+	err := t.TakeWithExpireCtx(ctx, &val, key, func(v interface{}, expire time.Duration) error {
+		loaded, err := query(expire)
+		if err != nil {
+			return err
+		}
+
+		*(v.(*T)) = loaded
+		return nil
+	})
+
+	return val, err
+}
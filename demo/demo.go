@@ -5,7 +5,11 @@ import (
 	"google.golang.org/appengine/log"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
 	"syscall"
+
+	"github.com/zeromicro/go-zero/core/service"
 )
 
 var serverList = []Server{
@@ -14,17 +18,30 @@ var serverList = []Server{
 }
 
 type Server interface {
-	Start(ctx context.Context) (Server, error)	//服务启动
-	ID() int								//返回服务标志，用于循序启动
-	Close() error	//关闭服务
-	IsOpen() bool	//是否开启
-}
-
-//开启服务
-func Start(ctx context.Context) {
-	for _, v := range serverList {
-		v.Start(ctx)
+	Start(ctx context.Context) (Server, error) //服务启动
+	ID() int                                   //返回服务标志，用于循序启动
+	DependsOn() []int                          //依赖的服务 ID 列表，启动前必须等这些服务启动并 Ready
+	Close() error                              //关闭服务
+	IsOpen() bool                              //是否开启
+}
+
+// HealthChecker 是一个可选接口，实现了它的 Server 在被其他服务依赖时
+// 必须等 Ready 返回 true 才算真正启动完成；未实现的服务 Start 一返回就视为就绪。
+type HealthChecker interface {
+	Ready() bool
+}
+
+// 开启服务
+//
+// Start returns the booted *Manager so callers can later Resolve a handle
+// by ID (e.g. a *RedisService a cache node wants) or Stop everything in
+// reverse start order; discarding it would make both unreachable.
+func Start(ctx context.Context) (*Manager, error) {
+	m := NewManager()
+	if err := m.Boot(ctx, serverList); err != nil {
+		return nil, err
 	}
+	return m, nil
 }
 
 type ServerNum uint8
@@ -35,23 +52,88 @@ const (
 	MaxServerNum
 )
 
-//服务管理
+// 服务管理
 type Manager struct {
+	mu      sync.Mutex
+	handles map[int]Server //已启动服务的句柄，key 为 Server.ID()
+	order   []int          //启动时算出的拓扑序，Stop 时逆序使用
+}
 
+func NewManager() *Manager {
+	return &Manager{handles: make(map[int]Server)}
 }
 
 func Loop() {
 	exit := make(chan os.Signal, 1)
 	signal.Notify(exit, syscall.SIGINT, syscall.SIGTERM)
 	select {
-		case sig := <- exit:
-			log.Infof(context.Background(), "recv signal %s", sig.String())
+	case sig := <-exit:
+		log.Infof(context.Background(), "recv signal %s", sig.String())
 	}
 }
 
-//服务启动入口
-func (m *Manager)Start(ctx context.Context, s Server) error {
-	if s == nil || !s.IsOpen() {	//未设置对象或未开启则直接退出
+// Boot 按 DependsOn 声明的依赖关系并发启动所有 servers：每个服务在自己的
+// goroutine 里等待它依赖的服务启动完成（若实现了 HealthChecker，还要等
+// Ready）之后再调用自己的 Start，不等待整层甚至全部服务的 Start 返回 --
+// 真实的 Server.Start 通常会一直阻塞（例如持续处理请求的服务），这里不能
+// 等任何一层"启动完成"再进入下一层。出现依赖环时直接返回诊断错误，不启
+// 动任何服务。
+func (m *Manager) Boot(ctx context.Context, servers []Server) error {
+	n := len(servers)
+	name := func(i int) string { return strconv.Itoa(servers[i].ID()) }
+	deps := func(i int) []string {
+		ids := servers[i].DependsOn()
+		out := make([]string, len(ids))
+		for j, id := range ids {
+			out[j] = strconv.Itoa(id)
+		}
+		return out
+	}
+
+	if _, err := service.Levels(n, name, deps); err != nil {
+		return err
+	}
+
+	byID := make(map[int]int, n)
+	for i, s := range servers {
+		byID[s.ID()] = i
+	}
+
+	m.mu.Lock()
+	m.order = m.order[:0]
+	m.mu.Unlock()
+
+	done := make([]chan struct{}, n)
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	for i, s := range servers {
+		i, s := i, s
+		go func() {
+			defer close(done[i])
+
+			for _, depID := range s.DependsOn() {
+				if j, ok := byID[depID]; ok {
+					service.WaitStarted(done[j], servers[j])
+				}
+			}
+
+			if s == nil || !s.IsOpen() {
+				return
+			}
+			if err := m.Start(ctx, s); err != nil {
+				panic(err)
+			}
+		}()
+	}
+
+	return nil
+}
+
+// 服务启动入口
+func (m *Manager) Start(ctx context.Context, s Server) error {
+	if s == nil || !s.IsOpen() { //未设置对象或未开启则直接退出
 		return nil
 	}
 	v, err := s.Start(ctx)
@@ -59,13 +141,37 @@ func (m *Manager)Start(ctx context.Context, s Server) error {
 		panic(err)
 	}
 
-	key := v.ID()	//构建k/v进行服务句柄存储
-	//TODO 这里使用 封装的context
+	key := v.ID() //构建k/v进行服务句柄存储
+	m.mu.Lock()
+	m.handles[key] = v
+	m.order = append(m.order, key)
+	m.mu.Unlock()
 	return nil
 }
 
-type Server1 struct {
+// Stop 按启动顺序的逆序关闭所有已启动的服务句柄。
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	order := append([]int(nil), m.order...)
+	m.mu.Unlock()
+
+	for i := len(order) - 1; i >= 0; i-- {
+		if s, ok := m.handles[order[i]]; ok {
+			s.Close()
+		}
+	}
+}
 
+// Resolve returns the Server registered under id, e.g. a *RedisService
+// another service depends on and wants the concrete handle for.
+func (m *Manager) Resolve(id int) (Server, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.handles[id]
+	return s, ok
+}
+
+type Server1 struct {
 }
 
 func (s *Server1) Start(ctx context.Context) (Server, error) {
@@ -77,6 +183,10 @@ func (s *Server1) ID() int {
 	return 0
 }
 
+func (s *Server1) DependsOn() []int {
+	return nil
+}
+
 func (s *Server1) Close() error {
 	//服务关闭
 	return nil
@@ -89,7 +199,6 @@ func (s *Server1) IsOpen() bool {
 var _ Server = (*Server1)(nil)
 
 type Server2 struct {
-
 }
 
 func (s *Server2) Start(ctx context.Context) (Server, error) {
@@ -101,6 +210,11 @@ func (s *Server2) ID() int {
 	return 1
 }
 
+func (s *Server2) DependsOn() []int {
+	//Server2 依赖 Server1 先启动
+	return []int{0}
+}
+
 func (s *Server2) Close() error {
 	//服务关闭
 	return nil
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/zeromicro/go-zero/core/service"
+)
+
+// pingTimeout bounds how long RedisService.Start waits for Redis to
+// answer a Ping before giving up on booting.
+const pingTimeout = 3 * time.Second
+
+// ConfigClient describes one Redis connection. URL, when set, is parsed
+// as a redis:// DSN (e.g. "redis://:pwd@127.0.0.1:6379/0") and takes
+// priority over the discrete fields, except a positive PoolSize, which
+// overrides whatever the DSN carries.
+type ConfigClient struct {
+	Addr     string
+	Password string
+	DB       int
+	PoolSize int
+	URL      string
+}
+
+func (c ConfigClient) buildOptions() (*redis.Options, error) {
+	if len(c.URL) > 0 {
+		opts, err := redis.ParseURL(c.URL)
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid url %q: %w", c.URL, err)
+		}
+		if c.PoolSize > 0 {
+			opts.PoolSize = c.PoolSize
+		}
+		return opts, nil
+	}
+
+	return &redis.Options{
+		Addr:     c.Addr,
+		Password: c.Password,
+		DB:       c.DB,
+		PoolSize: c.PoolSize,
+	}, nil
+}
+
+// RedisService owns a *redis.Client for the life of the process: Start
+// dials it and Pings with a bounded timeout so a bad config fails the
+// boot instead of surfacing as a mysterious cache miss later, and Close
+// cleanly drains the connection pool on shutdown. It implements Server so
+// the Manager's ordered startup registers it by ID the same way as any
+// other service, letting cache nodes resolve it back off the Manager
+// instead of dialing Redis themselves.
+type RedisService struct {
+	id     int
+	deps   []int
+	conf   ConfigClient
+	client *redis.Client
+	ready  int32 // atomic bool, set once Start's Ping succeeds
+}
+
+// NewRedisService returns a RedisService identified by id and configured
+// by conf, depending on the services named by deps.
+func NewRedisService(id int, conf ConfigClient, deps ...int) *RedisService {
+	return &RedisService{id: id, conf: conf, deps: deps}
+}
+
+func (r *RedisService) ID() int {
+	return r.id
+}
+
+func (r *RedisService) DependsOn() []int {
+	return r.deps
+}
+
+func (r *RedisService) IsOpen() bool {
+	return true
+}
+
+// Start dials conf and Pings it with a bounded timeout before returning,
+// so Boot fails fast on a dead Redis instead of only discovering it on
+// the first cache lookup.
+func (r *RedisService) Start(ctx context.Context) (Server, error) {
+	opts, err := r.conf.buildOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(opts)
+
+	pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	if err := client.Ping(pingCtx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("redis: ping %s failed: %w", opts.Addr, err)
+	}
+
+	r.client = client
+	atomic.StoreInt32(&r.ready, 1)
+	return r, nil
+}
+
+// Ready reports whether Start has Pinged Redis successfully; services
+// that DependsOn this one block until it returns true.
+func (r *RedisService) Ready() bool {
+	return atomic.LoadInt32(&r.ready) == 1
+}
+
+// Close drains and closes the connection pool.
+func (r *RedisService) Close() error {
+	if r.client == nil {
+		return nil
+	}
+	return r.client.Close()
+}
+
+// Client returns the underlying *redis.Client, e.g. for a cache node that
+// resolved this RedisService back off the Manager by ID.
+func (r *RedisService) Client() *redis.Client {
+	return r.client
+}
+
+// Iterator returns an Iterator over every key matching match, SCANning
+// count keys per round-trip so enumerating a large keyspace never blocks
+// Redis the way a single KEYS * would.
+func (r *RedisService) Iterator(ctx context.Context, match string, count int64) *Iterator {
+	return &Iterator{scan: r.client.Scan(ctx, 0, match, count).Iterator()}
+}
+
+// AsService adapts r to core/service.Service, so it can be added to a
+// service.ServiceGroup alongside services managed that way. Server and
+// Service can't both be implemented directly by RedisService: they each
+// declare an incompatible Start method, so the adaptation goes through
+// this small wrapper instead.
+func (r *RedisService) AsService(ctx context.Context) service.Service {
+	return redisServiceAdapter{ctx: ctx, svc: r}
+}
+
+// redisServiceAdapter bridges RedisService's ctx-aware Server lifecycle
+// to service.Service's no-arg Start/Stop shape, while still exposing
+// Named/DependencyAware/HealthChecker so a service.ServiceGroup can order
+// it. Its name is "redis#<id>" and DependsOn names each dep id the same
+// way, so within a ServiceGroup only dependencies on other RedisService
+// adapters are resolved; any other dep id matches nothing and is ignored.
+type redisServiceAdapter struct {
+	ctx context.Context
+	svc *RedisService
+}
+
+func (a redisServiceAdapter) Start() {
+	if _, err := a.svc.Start(a.ctx); err != nil {
+		panic(err)
+	}
+}
+
+func (a redisServiceAdapter) Stop() {
+	if err := a.svc.Close(); err != nil {
+		log.Printf("redis: close failed: %v", err)
+	}
+}
+
+func (a redisServiceAdapter) Name() string {
+	return fmt.Sprintf("redis#%d", a.svc.id)
+}
+
+func (a redisServiceAdapter) DependsOn() []string {
+	names := make([]string, len(a.svc.deps))
+	for i, id := range a.svc.deps {
+		names[i] = fmt.Sprintf("redis#%d", id)
+	}
+	return names
+}
+
+func (a redisServiceAdapter) Ready() bool {
+	return a.svc.Ready()
+}
+
+var _ Server = (*RedisService)(nil)
+var _ service.Service = redisServiceAdapter{}
+
+// Iterator walks a Redis keyspace with SCAN instead of KEYS, so callers
+// can enumerate cached keys in batches without ever blocking the server.
+type Iterator struct {
+	scan *redis.ScanIterator
+}
+
+// Next advances the iterator; it returns false once every matching key
+// has been visited or the scan failed (check Err).
+func (it *Iterator) Next(ctx context.Context) bool {
+	return it.scan.Next(ctx)
+}
+
+// Key returns the key Next just advanced to.
+func (it *Iterator) Key() string {
+	return it.scan.Val()
+}
+
+// Err returns the first error, if any, the underlying SCAN hit.
+func (it *Iterator) Err() error {
+	return it.scan.Err()
+}
@@ -1,37 +1,131 @@
 /*
-   @brief:
-   @date:2022/7/17
+@brief:
+@date:2022/7/17
 */
 package read_go_zero
 
-import "container/list"
+import (
+	"github.com/zeromicro/go-zero/core/service"
+)
 
-const (
+// Service 定义了一个可被 ServiceGroup 编排启停的服务。
+type Service interface {
+	Name() string            //服务名称
+	IsOpen() bool            //服务是否开启
+	DependsOn() []string     //依赖的服务名称，启动前会先等这些服务启动并 Ready
+	Start() (Service, error) //服务开启
+	Stop() error             //服务停止
+}
 
-)
+// HealthChecker 是一个可选接口，实现了它的 Service 在被其他服务依赖时
+// 必须等 Ready 返回 true 才算真正启动完成；未实现的服务 Start 一返回就视为就绪。
+type HealthChecker interface {
+	Ready() bool
+}
 
+// ServiceGroup 按照 Service.DependsOn 声明的依赖关系编排启停顺序：
+// 无依赖的服务并行启动，依赖方会等待所有前置服务启动（若实现了
+// HealthChecker，还要等 Ready）之后才启动；停止时按启动顺序的逆序依次
+// Stop。如果依赖关系存在环，Start 直接带上完整的环路诊断返回错误，不会
+// 启动任何服务。
 type ServiceGroup struct {
-	ServiceList []Service	//服务列表
+	ServiceList []Service //服务列表
 }
 
-type Service interface {
-	Name() string		//服务名称
-	IsOpen() bool		//服务是否开启
-	Start() (Service,error)		//服务开启
-	Stop() error		//服务停止
+// NewServiceGroup returns a ServiceGroup.
+func NewServiceGroup() *ServiceGroup {
+	return &ServiceGroup{}
 }
 
-func NewServiceGroup() *ServiceGroup {
-	return &ServiceGroup{ServiceList:}
+// Add 添加服务。
+func (s *ServiceGroup) Add(svc Service) {
+	s.ServiceList = append(s.ServiceList, svc)
 }
 
+// Start 按依赖关系拓扑排序后并发启动所有服务：每个服务在自己的 goroutine
+// 里等待它依赖的服务启动完成（若实现了 HealthChecker，还要等 Ready）之后
+// 再调用自己的 Start，不等待整层甚至全部服务的 Start 返回 -- 真实的
+// Service.Start 通常会一直阻塞（例如持续处理请求的服务），这里不能等任何
+// 一层"启动完成"再进入下一层。f 在每个服务自己的 Start 调用返回后被调用，
+// 用于上报启动结果。如果依赖关系存在环，直接带上完整的环路诊断返回错误，
+// 不会启动任何服务。
+func (s *ServiceGroup) Start(f func(service Service, err error)) error {
+	n := len(s.ServiceList)
+	name := func(i int) string { return s.ServiceList[i].Name() }
+	deps := func(i int) []string { return s.ServiceList[i].DependsOn() }
+
+	if _, err := service.Levels(n, name, deps); err != nil {
+		return err
+	}
+
+	byName := make(map[string]int, n)
+	for i, svc := range s.ServiceList {
+		byName[svc.Name()] = i
+	}
+
+	done := make([]chan struct{}, n)
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	for i := range s.ServiceList {
+		i := i
+		svc := s.ServiceList[i]
+		go func() {
+			defer close(done[i])
 
-func (s *ServiceGroup)Start(f func(service Service, err error)) {
+			for _, depName := range svc.DependsOn() {
+				if j, ok := byName[depName]; ok {
+					service.WaitStarted(done[j], s.ServiceList[j])
+				}
+			}
+
+			v, err := svc.Start()
+			if f != nil {
+				f(v, err)
+			}
+		}()
+	}
 
+	return nil
 }
 
-func (s *ServiceGroup)Stop() {
-	for i := len(s.ServiceList) - 1; i >= 0; i-- {
-		v.Stop()
+// Stop 按依赖关系的逆拓扑序停止服务，被依赖的服务最后停止。
+func (s *ServiceGroup) Stop() {
+	levels, err := s.levels()
+	if err != nil {
+		// 依赖图里有环，建不出拓扑序，按添加顺序逆序兜底停止。
+		for i := len(s.ServiceList) - 1; i >= 0; i-- {
+			s.ServiceList[i].Stop()
+		}
+		return
 	}
-}
\ No newline at end of file
+
+	for i := len(levels) - 1; i >= 0; i-- {
+		for _, svc := range levels[i] {
+			svc.Stop()
+		}
+	}
+}
+
+// levels 把 ServiceList 按依赖关系分层，同一层内没有先后顺序要求。
+func (s *ServiceGroup) levels() ([][]Service, error) {
+	n := len(s.ServiceList)
+	idxLevels, err := service.Levels(n,
+		func(i int) string { return s.ServiceList[i].Name() },
+		func(i int) []string { return s.ServiceList[i].DependsOn() })
+	if err != nil {
+		return nil, err
+	}
+
+	levels := make([][]Service, len(idxLevels))
+	for i, idx := range idxLevels {
+		level := make([]Service, len(idx))
+		for j, k := range idx {
+			level[j] = s.ServiceList[k]
+		}
+		levels[i] = level
+	}
+
+	return levels, nil
+}